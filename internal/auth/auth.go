@@ -0,0 +1,39 @@
+// Package auth provides pluggable schemes for authenticating gateway
+// requests and resolving them to a virtual key.
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gateway/internal/config"
+)
+
+// Auth validates an incoming request and returns the virtual key it
+// authenticates as.
+type Auth interface {
+	Validate(r *http.Request) (virtualKey string, err error)
+}
+
+// NewAuth builds an Auth implementation from a scheme string of the form
+// "<scheme>://<params>", e.g. "static://", "basicfile:///etc/gateway/htpasswd",
+// or "cert://". store is consulted on every Validate call, so a reloaded
+// keys file takes effect immediately.
+func NewAuth(scheme string, store *config.Store) (Auth, error) {
+	name, params, found := strings.Cut(scheme, "://")
+	if !found {
+		return nil, fmt.Errorf("invalid auth scheme %q: expected \"<scheme>://<params>\"", scheme)
+	}
+
+	switch name {
+	case "static":
+		return NewStatic(store), nil
+	case "basicfile":
+		return NewBasicFile(params, store)
+	case "cert":
+		return NewCert(store), nil
+	default:
+		return nil, fmt.Errorf("unsupported auth scheme: %s", name)
+	}
+}
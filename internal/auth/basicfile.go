@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"gateway/internal/config"
+)
+
+// BasicFile authenticates requests via HTTP Basic Auth against an htpasswd-
+// style file of "<virtual-key>:<bcrypt-hash>" lines. The Basic Auth username
+// is the virtual key itself.
+type BasicFile struct {
+	path  string
+	store *config.Store
+
+	mu     sync.RWMutex
+	hashes map[string]string // virtual key -> bcrypt hash
+}
+
+// NewBasicFile loads credentials from path and returns a BasicFile auth
+// scheme backed by store for virtual key lookups.
+func NewBasicFile(path string, store *config.Store) (*BasicFile, error) {
+	b := &BasicFile{path: path, store: store}
+	if err := b.reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// reload re-reads the credentials file from disk.
+func (b *BasicFile) reload() error {
+	file, err := os.Open(b.path)
+	if err != nil {
+		return fmt.Errorf("failed to open basicfile credentials %q: %w", b.path, err)
+	}
+	defer file.Close()
+
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, found := strings.Cut(line, ":")
+		if !found {
+			return fmt.Errorf("malformed basicfile credentials line: %q", line)
+		}
+		hashes[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read basicfile credentials %q: %w", b.path, err)
+	}
+
+	b.mu.Lock()
+	b.hashes = hashes
+	b.mu.Unlock()
+	return nil
+}
+
+// Validate implements Auth.
+func (b *BasicFile) Validate(r *http.Request) (string, error) {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return "", fmt.Errorf("missing or invalid Basic Auth header")
+	}
+
+	b.mu.RLock()
+	hash, exists := b.hashes[user]
+	b.mu.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("unknown basic auth user: %s", user)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)); err != nil {
+		return "", fmt.Errorf("invalid credentials for user: %s", user)
+	}
+
+	if _, exists := b.store.Get().VirtualKeys[user]; !exists {
+		return "", fmt.Errorf("basic auth user has no matching virtual key: %s", user)
+	}
+
+	return user, nil
+}
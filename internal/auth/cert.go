@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"gateway/internal/config"
+)
+
+// Cert authenticates requests via the client TLS certificate presented
+// during the handshake, using its Subject CN (falling back to the first
+// DNS SAN) as the virtual key. This only works when the gateway is served
+// over TLS with ClientAuth: tls.RequireAndVerifyClientCert, which main.go
+// sets up automatically when AUTH_SCHEME is cert://.
+type Cert struct {
+	store *config.Store
+}
+
+// NewCert creates a Cert auth scheme backed by store.
+func NewCert(store *config.Store) *Cert {
+	return &Cert{store: store}
+}
+
+// Validate implements Auth.
+func (c *Cert) Validate(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return "", fmt.Errorf("no client certificate presented")
+	}
+
+	cert := r.TLS.PeerCertificates[0]
+	virtualKey := cert.Subject.CommonName
+	if virtualKey == "" && len(cert.DNSNames) > 0 {
+		virtualKey = cert.DNSNames[0]
+	}
+	if virtualKey == "" {
+		return "", fmt.Errorf("client certificate has no usable CN or SAN")
+	}
+
+	if _, exists := c.store.Get().VirtualKeys[virtualKey]; !exists {
+		return "", fmt.Errorf("no virtual key matching certificate identity: %s", virtualKey)
+	}
+
+	return virtualKey, nil
+}
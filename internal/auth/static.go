@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gateway/internal/config"
+)
+
+// Static authenticates requests against a literal "Bearer <virtual-key>"
+// header, matched directly against the configured virtual keys.
+type Static struct {
+	store *config.Store
+}
+
+// NewStatic creates a Static auth scheme backed by store.
+func NewStatic(store *config.Store) *Static {
+	return &Static{store: store}
+}
+
+// Validate implements Auth.
+func (s *Static) Validate(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", fmt.Errorf("missing Authorization header")
+	}
+
+	virtualKey := strings.TrimPrefix(authHeader, "Bearer ")
+	if virtualKey == authHeader {
+		return "", fmt.Errorf("invalid Authorization header format, expected 'Bearer <virtual-key>'")
+	}
+
+	if _, exists := s.store.Get().VirtualKeys[virtualKey]; !exists {
+		return "", fmt.Errorf("invalid virtual key")
+	}
+
+	return virtualKey, nil
+}
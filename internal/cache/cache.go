@@ -0,0 +1,26 @@
+// Package cache stores chat-completion responses keyed by a content
+// address of the parts of the request that determine the response, so
+// repeated deterministic requests can be served without hitting the
+// upstream provider.
+package cache
+
+import "time"
+
+// Entry is a stored response: status code, a filtered set of headers, and
+// the raw body.
+type Entry struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers"`
+	Body    []byte              `json:"body"`
+}
+
+// Cache stores and retrieves response Entry values by key. Implementations
+// must be safe for concurrent use.
+type Cache interface {
+	// Get returns the entry stored under key, if present and not expired.
+	Get(key string) (Entry, bool)
+
+	// Set stores entry under key. A ttl of zero uses the backend's default
+	// TTL.
+	Set(key string, entry Entry, ttl time.Duration)
+}
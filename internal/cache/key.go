@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// keyFields is the subset of a chat-completion request that determines
+// whether two requests are cache-equivalent. encoding/json sorts map keys
+// when marshaling, so this produces a stable hash regardless of the
+// original field order in the request body.
+type keyFields struct {
+	Provider    string      `json:"provider"`
+	Model       interface{} `json:"model"`
+	Messages    interface{} `json:"messages"`
+	Temperature interface{} `json:"temperature"`
+	TopP        interface{} `json:"top_p"`
+	MaxTokens   interface{} `json:"max_tokens"`
+	Tools       interface{} `json:"tools"`
+}
+
+// Key computes a content-addressed cache key for a chat-completion request
+// made against provider, hashing the fields of requestJSON that affect the
+// response.
+func Key(provider string, requestJSON map[string]interface{}) string {
+	fields := keyFields{
+		Provider:    provider,
+		Model:       requestJSON["model"],
+		Messages:    requestJSON["messages"],
+		Temperature: requestJSON["temperature"],
+		TopP:        requestJSON["top_p"],
+		MaxTokens:   requestJSON["max_tokens"],
+		Tools:       requestJSON["tools"],
+	}
+
+	// Marshaling values parsed from JSON back to JSON cannot fail.
+	raw, _ := json.Marshal(fields)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process LRU cache with a per-entry TTL, suitable for a
+// single gateway instance.
+type Memory struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryItem struct {
+	key       string
+	entry     Entry
+	expiresAt time.Time
+}
+
+// NewMemory creates an in-memory LRU cache holding up to capacity entries,
+// each valid for ttl unless Set is called with an explicit override.
+func NewMemory(capacity int, ttl time.Duration) *Memory {
+	return &Memory{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get implements Cache.
+func (m *Memory) Get(key string) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+
+	item := el.Value.(*memoryItem)
+	if time.Now().After(item.expiresAt) {
+		m.order.Remove(el)
+		delete(m.items, key)
+		return Entry{}, false
+	}
+
+	m.order.MoveToFront(el)
+	return item.entry, true
+}
+
+// Set implements Cache.
+func (m *Memory) Set(key string, entry Entry, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ttl <= 0 {
+		ttl = m.ttl
+	}
+	item := &memoryItem{key: key, entry: entry, expiresAt: time.Now().Add(ttl)}
+
+	if el, ok := m.items[key]; ok {
+		el.Value = item
+		m.order.MoveToFront(el)
+		return
+	}
+
+	el := m.order.PushFront(item)
+	m.items[key] = el
+
+	if m.capacity > 0 && m.order.Len() > m.capacity {
+		oldest := m.order.Back()
+		if oldest != nil {
+			m.order.Remove(oldest)
+			delete(m.items, oldest.Value.(*memoryItem).key)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Cache backend storing entries in Redis, so a cache can be
+// shared across multiple gateway instances.
+type Redis struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedis creates a Redis-backed cache using client, applying ttl to
+// entries unless Set is called with an explicit override.
+func NewRedis(client *redis.Client, ttl time.Duration) *Redis {
+	return &Redis{client: client, ttl: ttl}
+}
+
+// Get implements Cache.
+func (r *Redis) Get(key string) (Entry, bool) {
+	raw, err := r.client.Get(context.Background(), key).Bytes()
+	if err != nil {
+		return Entry{}, false
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Set implements Cache.
+func (r *Redis) Set(key string, entry Entry, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = r.ttl
+	}
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	r.client.Set(context.Background(), key, raw, ttl)
+}
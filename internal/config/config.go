@@ -1,15 +1,29 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
+
+	"gateway/internal/provider"
+	"gateway/internal/usage"
 )
 
 // KeyConfig represents a single virtual key configuration
 type KeyConfig struct {
-	Provider string `json:"provider"`
-	APIKey   string `json:"api_key"`
+	Provider string       `json:"provider"`
+	APIKey   string       `json:"api_key"`
+	Policy   usage.Policy `json:"policy"`
+
+	// Fallback lists providers to try, in order, once retries against
+	// Provider are exhausted.
+	Fallback []string `json:"fallback,omitempty"`
+
+	// BaseURL is the endpoint used when Provider (or an entry of Fallback)
+	// is "generic", for OpenAI-compatible backends with no dedicated
+	// provider implementation (Ollama, vLLM, LM Studio, Together, etc.).
+	BaseURL string `json:"base_url,omitempty"`
 }
 
 // Config represents the keys.json structure
@@ -24,6 +38,10 @@ func Load(filepath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	if err := checkDuplicateVirtualKeys(file); err != nil {
+		return nil, err
+	}
+
 	var config Config
 	if err := json.Unmarshal(file, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config JSON: %w", err)
@@ -31,3 +49,67 @@ func Load(filepath string) (*Config, error) {
 
 	return &config, nil
 }
+
+// checkDuplicateVirtualKeys walks the raw "virtual_keys" object token by
+// token to catch literal duplicate keys in the JSON source, which
+// encoding/json would otherwise silently resolve to the last occurrence.
+func checkDuplicateVirtualKeys(raw []byte) error {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil // malformed JSON is reported by the real Unmarshal below
+	}
+	vkRaw, ok := root["virtual_keys"]
+	if !ok {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(vkRaw))
+	if _, err := dec.Token(); err != nil { // consume opening '{'
+		return nil
+	}
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil
+		}
+		if seen[key] {
+			return fmt.Errorf("duplicate virtual key in config: %s", key)
+		}
+		seen[key] = true
+
+		var discard json.RawMessage
+		if err := dec.Decode(&discard); err != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Validate checks a loaded configuration for errors that should block a
+// reload: unknown provider names and virtual keys with no API key.
+func Validate(cfg *Config) error {
+	registry := provider.NewRegistry()
+
+	for vk, kc := range cfg.VirtualKeys {
+		if kc.Provider == "" {
+			return fmt.Errorf("virtual key %q: missing provider", vk)
+		}
+		if _, err := registry.Get(kc.Provider, kc.BaseURL); err != nil {
+			return fmt.Errorf("virtual key %q: %w", vk, err)
+		}
+		if kc.APIKey == "" {
+			return fmt.Errorf("virtual key %q: empty api_key", vk)
+		}
+		for _, fb := range kc.Fallback {
+			if _, err := registry.Get(fb, kc.BaseURL); err != nil {
+				return fmt.Errorf("virtual key %q: %w", vk, err)
+			}
+		}
+	}
+	return nil
+}
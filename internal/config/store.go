@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Store holds the current configuration behind an atomic pointer so callers
+// always observe a consistent snapshot, while the keys file can be
+// hot-reloaded without restarting the gateway.
+type Store struct {
+	path    string
+	current atomic.Pointer[Config]
+}
+
+// NewStore loads and validates the configuration at path, returning a Store
+// serving it.
+func NewStore(path string) (*Store, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	s := &Store{path: path}
+	s.current.Store(cfg)
+	return s, nil
+}
+
+// Get returns the current configuration. Callers must not cache the result
+// across requests, since it may be swapped out by a reload at any time.
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Reload re-reads and validates the configuration file, swapping it in on
+// success. A failed reload is logged and the previous configuration is kept
+// in place rather than crashing the gateway.
+func (s *Store) Reload() error {
+	cfg, err := Load(s.path)
+	if err != nil {
+		slog.Error("Config reload failed, keeping previous configuration", "path", s.path, "error", err)
+		return err
+	}
+	if err := Validate(cfg); err != nil {
+		slog.Error("Config reload rejected invalid configuration, keeping previous configuration", "path", s.path, "error", err)
+		return err
+	}
+
+	s.current.Store(cfg)
+	slog.Info("Configuration reloaded", "path", s.path, "virtual_keys", len(cfg.VirtualKeys))
+	return nil
+}
+
+// Watch reloads the configuration whenever the keys file changes on disk or
+// the process receives SIGHUP. It spawns a background goroutine and returns
+// once the watch is established.
+//
+// It watches the file's parent directory rather than the file itself:
+// atomic saves (write a temp file, then rename it over the target) replace
+// the target's inode, which would silently end a watch on the file path
+// directly, since no further events would ever arrive for the old inode.
+func (s *Store) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config file watcher: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	base := filepath.Base(s.path)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config directory %q: %w", dir, err)
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) != base {
+					continue
+				}
+				// Handle in-place writes as well as atomic replace, which
+				// surfaces as a Rename of the old inode away followed by a
+				// Create at the target path.
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					_ = s.Reload()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("Config file watcher error", "error", err)
+			case <-sighup:
+				slog.Info("Received SIGHUP, reloading configuration")
+				_ = s.Reload()
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"gateway/internal/cache"
+	"gateway/internal/config"
+)
+
+// excludedCacheHeaders are response headers that shouldn't be replayed
+// verbatim from a cached entry, either because they describe the transport
+// of the original response rather than its content, or because the gateway
+// sets them itself when serving a hit.
+var excludedCacheHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Connection":        true,
+	"Transfer-Encoding": true,
+	"Date":              true,
+}
+
+// shouldUseCache reports whether r is eligible for the response cache: it
+// must not be a streamed request, and must either opt in via the X-Cache
+// header or use temperature 0, which most providers treat as deterministic.
+func shouldUseCache(r *http.Request, requestJSON map[string]interface{}) bool {
+	if stream, _ := requestJSON["stream"].(bool); stream {
+		return false
+	}
+	if strings.EqualFold(r.Header.Get("X-Cache"), "enabled") {
+		return true
+	}
+	temperature, ok := requestJSON["temperature"].(float64)
+	return ok && temperature == 0
+}
+
+// filterCacheHeaders drops headers that shouldn't be stored alongside a
+// cache entry.
+func filterCacheHeaders(header http.Header) map[string][]string {
+	filtered := make(map[string][]string, len(header))
+	for name, values := range header {
+		if excludedCacheHeaders[name] {
+			continue
+		}
+		filtered[name] = values
+	}
+	return filtered
+}
+
+// cacheKey computes the content-addressed key for a request against
+// keyConfig's provider.
+func cacheKey(keyConfig config.KeyConfig, requestJSON map[string]interface{}) string {
+	return cache.Key(keyConfig.Provider, requestJSON)
+}
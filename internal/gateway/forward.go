@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"gateway/internal/config"
+	"gateway/internal/provider"
+	"gateway/internal/retry"
+)
+
+// requestedModel extracts the "model" field from a request body, for
+// providers (like Gemini) whose endpoint is model-specific.
+func requestedModel(body []byte) string {
+	var parsed struct {
+		Model string `json:"model"`
+	}
+	_ = json.Unmarshal(body, &parsed)
+	return parsed.Model
+}
+
+// buildProxyRequest builds the outbound request for prov, copying headers
+// from the original client request (excluding Authorization) and setting
+// prov's own authentication.
+func buildProxyRequest(r *http.Request, prov provider.Provider, apiKey string, body []byte, model string) (*http.Request, error) {
+	proxyReq, err := http.NewRequest(r.Method, prov.GetEndpoint(model), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proxy request: %w", err)
+	}
+
+	for name, values := range r.Header {
+		if name != "Authorization" {
+			for _, value := range values {
+				proxyReq.Header.Add(name, value)
+			}
+		}
+	}
+	prov.SetAuthHeaders(proxyReq, apiKey)
+
+	return proxyReq, nil
+}
+
+// forward sends requestBody to keyConfig's provider, retrying transient
+// failures with backoff. Once retries against a provider are exhausted it
+// falls through keyConfig.Fallback in order, translating the request (and,
+// for buffered responses, the response) between wire formats as needed. It
+// returns the final response, the provider that produced it, and a log of
+// every attempt made across the whole chain.
+//
+// If every provider in the chain is exhausted, forward still returns the
+// last upstream response it received (when it has one) alongside a non-nil
+// error, so the caller can surface the real status and body to the client
+// instead of a generic gateway error.
+func (g *Gateway) forward(r *http.Request, keyConfig config.KeyConfig, requestBody []byte) (*http.Response, provider.Provider, []retry.Attempt, error) {
+	client := &http.Client{}
+	chain := append([]string{keyConfig.Provider}, keyConfig.Fallback...)
+
+	var attempts []retry.Attempt
+	var lastErr error
+	var lastResp *http.Response
+	var lastProvider provider.Provider
+
+	for _, name := range chain {
+		candidate, err := g.providerRegistry.Get(name, keyConfig.BaseURL)
+		if err != nil {
+			slog.Warn("Skipping unknown fallback provider", "provider", name, "error", err)
+			lastErr = err
+			continue
+		}
+
+		translator, err := provider.TranslatorFor(keyConfig.Provider, name)
+		if err != nil {
+			slog.Warn("No translator registered, skipping fallback", "from", keyConfig.Provider, "to", name, "error", err)
+			lastErr = err
+			continue
+		}
+
+		translatedBody, err := translator.TranslateRequest(requestBody)
+		if err != nil {
+			slog.Warn("Failed to translate request", "provider", name, "error", err)
+			lastErr = err
+			continue
+		}
+
+		transformedBody, err := candidate.TransformRequest(translatedBody)
+		if err != nil {
+			slog.Warn("Failed to transform request for provider", "provider", name, "error", err)
+			lastErr = err
+			continue
+		}
+
+		model := requestedModel(translatedBody)
+		resp, candAttempts, err := retry.Do(r.Context(), retry.DefaultConfig, candidate.GetName(), func() (*http.Response, error) {
+			proxyReq, buildErr := buildProxyRequest(r, candidate, keyConfig.APIKey, transformedBody, model)
+			if buildErr != nil {
+				return nil, buildErr
+			}
+			return client.Do(proxyReq)
+		})
+		attempts = append(attempts, candAttempts...)
+
+		// retry.Do returns a response alongside a non-nil error when the
+		// chain's final attempt is still a retryable status. Keep it around
+		// in case every remaining provider also fails, so we have a real
+		// response to fall back to instead of a generic 502.
+		if resp != nil {
+			if lastResp != nil {
+				lastResp.Body.Close()
+			}
+			lastResp, lastProvider = resp, candidate
+		}
+
+		if err != nil {
+			lastErr = err
+			slog.Warn("Provider exhausted retries", "provider", candidate.GetName(), "error", err)
+			continue
+		}
+
+		// Streaming responses are relayed frame-by-frame and can't be
+		// re-chunked after translation, so only buffered responses are
+		// transformed back out of the provider's bespoke wire shape and
+		// translated back to the virtual key's original provider format.
+		isStream := strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/event-stream")
+		if !isStream {
+			transformed, terr := transformResponseBody(resp.Body, candidate)
+			if terr != nil {
+				resp.Body.Close()
+				lastErr = fmt.Errorf("failed to transform response from %s: %w", candidate.GetName(), terr)
+				slog.Warn("Failed to transform response", "provider", candidate.GetName(), "error", terr)
+				lastResp = nil
+				continue
+			}
+			resp.Body = transformed
+		}
+		if candidate.GetName() != keyConfig.Provider && !isStream {
+			var rerr error
+			resp.Body, rerr = translateResponseBody(resp.Body, translator)
+			if rerr != nil {
+				resp.Body.Close()
+				lastErr = fmt.Errorf("failed to translate response from %s: %w", candidate.GetName(), rerr)
+				slog.Warn("Failed to translate response", "provider", candidate.GetName(), "error", rerr)
+				lastResp = nil
+				continue
+			}
+		}
+
+		return resp, candidate, attempts, nil
+	}
+
+	if lastResp != nil {
+		return lastResp, lastProvider, attempts, fmt.Errorf("all providers exhausted: %w", lastErr)
+	}
+	return nil, nil, attempts, fmt.Errorf("all providers exhausted: %w", lastErr)
+}
+
+// transformResponseBody reads a response body and runs it through prov's
+// TransformResponse, returning a fresh ReadCloser over the result.
+func transformResponseBody(body io.ReadCloser, prov provider.Provider) (io.ReadCloser, error) {
+	raw, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	transformed, err := prov.TransformResponse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(transformed)), nil
+}
+
+// translateResponseBody reads and translates a response body, returning a
+// fresh ReadCloser over the translated bytes.
+func translateResponseBody(body io.ReadCloser, translator provider.Translator) (io.ReadCloser, error) {
+	raw, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	translated, err := translator.TranslateResponse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(translated)), nil
+}
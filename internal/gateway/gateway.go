@@ -1,14 +1,19 @@
 package gateway
 
 import (
-	"bytes"
+	"errors"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
+	"gateway/internal/auth"
+	"gateway/internal/cache"
 	"gateway/internal/logger"
+	"gateway/internal/metrics"
 	"gateway/internal/provider"
+	"gateway/internal/usage"
 	"gateway/internal/virtualkey"
 )
 
@@ -16,20 +21,51 @@ const chatCompletionsPath = "/chat/completions"
 
 // Gateway handles incoming requests and routes them to appropriate providers
 type Gateway struct {
+	auth             auth.Auth
 	vkService        *virtualkey.Service
 	providerRegistry *provider.Registry
 	logger           *logger.Logger
+	tracker          *usage.Tracker
+	metrics          *metrics.Recorder
+	cache            cache.Cache
 }
 
-// New creates a new Gateway instance
-func New(vkService *virtualkey.Service, providerRegistry *provider.Registry, log *logger.Logger) *Gateway {
+// New creates a new Gateway instance. cache may be nil to disable response
+// caching entirely.
+func New(authenticator auth.Auth, vkService *virtualkey.Service, providerRegistry *provider.Registry, log *logger.Logger, tracker *usage.Tracker, recorder *metrics.Recorder, respCache cache.Cache) *Gateway {
 	return &Gateway{
+		auth:             authenticator,
 		vkService:        vkService,
 		providerRegistry: providerRegistry,
 		logger:           log,
+		tracker:          tracker,
+		metrics:          recorder,
+		cache:            respCache,
 	}
 }
 
+// requestModel extracts the "model" field from a parsed request body, if any.
+func requestModel(requestJSON map[string]interface{}) string {
+	model, _ := requestJSON["model"].(string)
+	return model
+}
+
+// responseTokenUsage extracts prompt and completion token counts from a
+// parsed provider response's "usage" object, if present.
+func responseTokenUsage(responseJSON map[string]interface{}) (prompt, completion int) {
+	usageObj, ok := responseJSON["usage"].(map[string]interface{})
+	if !ok {
+		return 0, 0
+	}
+	if n, ok := usageObj["prompt_tokens"].(float64); ok {
+		prompt = int(n)
+	}
+	if n, ok := usageObj["completion_tokens"].(float64); ok {
+		completion = int(n)
+	}
+	return prompt, completion
+}
+
 // ServeHTTP implements the http.Handler interface
 func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Start timing
@@ -47,11 +83,11 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate virtual key
-	virtualKey, err := g.vkService.ValidateRequest(r.Header.Get("Authorization"))
+	// Validate virtual key via the configured auth scheme
+	virtualKey, err := g.auth.Validate(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
-		log.Printf("Request rejected: %v", err)
+		slog.Warn("Request rejected", "error", err)
 		return
 	}
 
@@ -59,7 +95,7 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	keyConfig, exists := g.vkService.GetKeyConfig(virtualKey)
 	if !exists {
 		http.Error(w, "Invalid virtual key", http.StatusUnauthorized)
-		log.Printf("Request rejected: virtual key not found: %s", virtualKey)
+		slog.Warn("Request rejected: virtual key not found", "virtual_key", virtualKey)
 		return
 	}
 
@@ -67,7 +103,7 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	requestBody, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
-		log.Printf("Error reading request body: %v", err)
+		slog.Error("Error reading request body", "error", err)
 		return
 	}
 	defer r.Body.Close()
@@ -75,60 +111,115 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Parse request JSON for logging
 	requestJSON := logger.ParseJSONBody(requestBody, r.Header.Get("Content-Encoding"))
 
-	// Get provider
-	prov, err := g.providerRegistry.Get(keyConfig.Provider)
+	// Serve deterministic requests from cache when possible, bypassing the
+	// provider (and its rate limits) entirely.
+	useCache := g.cache != nil && shouldUseCache(r, requestJSON)
+	if useCache {
+		if entry, hit := g.cache.Get(cacheKey(keyConfig, requestJSON)); hit {
+			for name, values := range entry.Headers {
+				for _, value := range values {
+					w.Header().Add(name, value)
+				}
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.WriteHeader(entry.Status)
+			if _, err := w.Write(entry.Body); err != nil {
+				slog.Error("Error writing cached response to client", "error", err)
+			}
+
+			duration := time.Since(startTime)
+			g.metrics.ObserveRequest(virtualKey, keyConfig.Provider, entry.Status, duration)
+			slog.Info("Served cached response", "virtual_key", virtualKey, "provider", keyConfig.Provider, "duration_ms", duration.Milliseconds())
+			return
+		}
+	}
+
+	// Reserve rate-limit and token-bucket capacity for this virtual key/model
+	model := requestModel(requestJSON)
+	reservation, err := g.tracker.Reserve(virtualKey, model, keyConfig.Policy)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("Error getting provider: %v", err)
+		var limitErr *usage.LimitError
+		if errors.As(err, &limitErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(limitErr.RetryAfter.Seconds())))
+		}
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		slog.Warn("Request rejected", "error", err)
+		g.metrics.ObserveRequest(virtualKey, keyConfig.Provider, http.StatusTooManyRequests, time.Since(startTime))
 		return
 	}
 
-	log.Printf("Routing request to %s provider (virtual key: %s)", prov.GetName(), virtualKey)
-
-	// Create proxy request
-	proxyReq, err := http.NewRequest(r.Method, prov.GetEndpoint(), bytes.NewReader(requestBody))
-	if err != nil {
-		http.Error(w, "Failed to create proxy request", http.StatusInternalServerError)
-		log.Printf("Error creating proxy request: %v", err)
+	// Forward the request, retrying with backoff and falling over to any
+	// configured fallback providers
+	resp, prov, attempts, err := g.forward(r, keyConfig, requestBody)
+	if resp == nil {
+		// No provider in the chain ever produced a response (connection
+		// failures, translation errors, or an unconfigured/unknown
+		// provider) rather than an exhausted-but-responsive upstream, so
+		// there's nothing real to relay to the client.
+		http.Error(w, "Failed to forward request", http.StatusBadGateway)
+		slog.Error("Error forwarding request", "error", err)
+		g.metrics.ObserveUpstreamError(keyConfig.Provider, "forward_exhausted")
+		g.metrics.ObserveRequest(virtualKey, keyConfig.Provider, http.StatusBadGateway, time.Since(startTime))
+		// The reservation's estimated token was already debited; since no
+		// provider ever reported real usage, true it up to zero instead of
+		// leaking it until the bucket refills on its own. The request-rate
+		// slot stays spent.
+		g.tracker.Commit(reservation, 0)
 		return
 	}
+	defer resp.Body.Close()
 
-	// Copy headers from original request (excluding Authorization)
-	for name, values := range r.Header {
-		if name != "Authorization" {
-			for _, value := range values {
-				proxyReq.Header.Add(name, value)
-			}
-		}
+	if err != nil {
+		// Every provider in the chain was exhausted, but the last one did
+		// respond; relay its actual status and body rather than rewriting
+		// it to a generic 502.
+		slog.Warn("All providers exhausted, surfacing last upstream response", "provider", prov.GetName(), "error", err)
+		g.metrics.ObserveUpstreamError(keyConfig.Provider, "forward_exhausted")
 	}
 
-	// Set provider-specific authentication headers
-	prov.SetAuthHeaders(proxyReq, keyConfig.APIKey)
+	slog.Info("Routed request", "provider", prov.GetName(), "virtual_key", virtualKey)
 
-	// Forward the request
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
-	if err != nil {
-		http.Error(w, "Failed to forward request", http.StatusBadGateway)
-		log.Printf("Error forwarding request: %v", err)
+	// Streamed responses (SSE) must be relayed frame-by-frame rather than
+	// buffered, since the provider keeps the connection open indefinitely.
+	if isStreamingResponse(requestJSON, resp) {
+		g.streamResponse(w, resp, reservation, startTime, virtualKey, prov, requestJSON, attempts)
 		return
 	}
-	defer resp.Body.Close()
 
 	// Read and buffer the response body
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		http.Error(w, "Failed to read response body", http.StatusBadGateway)
-		log.Printf("Error reading response body: %v", err)
+		slog.Error("Error reading response body", "error", err)
+		g.tracker.Commit(reservation, 0)
 		return
 	}
 
 	// Parse response body for logging (handles decompression internally)
 	responseJSON := logger.ParseJSONBody(responseBody, resp.Header.Get("Content-Encoding"))
 
+	// Store successful, cacheable responses for future deterministic requests
+	if useCache && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		g.cache.Set(cacheKey(keyConfig, requestJSON), cache.Entry{
+			Status:  resp.StatusCode,
+			Headers: filterCacheHeaders(resp.Header),
+			Body:    responseBody,
+		}, 0)
+	}
+
+	// True up the token bucket with actual usage reported by the provider
+	promptTokens, completionTokens := responseTokenUsage(responseJSON)
+	g.tracker.Commit(reservation, promptTokens+completionTokens)
+
 	// Calculate duration
 	duration := time.Since(startTime)
 
+	// Record metrics for this request
+	g.metrics.ObserveRequest(virtualKey, prov.GetName(), resp.StatusCode, duration)
+	g.metrics.ObserveTokens(virtualKey, prov.GetName(), "prompt", promptTokens)
+	g.metrics.ObserveTokens(virtualKey, prov.GetName(), "completion", completionTokens)
+	g.metrics.SetQuotaRemaining(virtualKey, g.tracker.RemainingTokens(virtualKey, model))
+
 	// Create and log interaction
 	logEntry := logger.LogEntry{
 		Timestamp:  startTime.Format(time.RFC3339),
@@ -139,6 +230,7 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		DurationMs: duration.Milliseconds(),
 		Request:    requestJSON,
 		Response:   responseJSON,
+		Attempts:   attempts,
 	}
 	g.logger.LogInteraction(logEntry)
 
@@ -152,9 +244,8 @@ func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Set status code and write response
 	w.WriteHeader(resp.StatusCode)
 	if _, err := w.Write(responseBody); err != nil {
-		log.Printf("Error writing response body to client: %v", err)
+		slog.Error("Error writing response body to client", "error", err)
 	}
 
-	log.Printf("Request completed with status: %d (provider: %s, duration: %dms)",
-		resp.StatusCode, prov.GetName(), duration.Milliseconds())
+	slog.Info("Request completed", "status", resp.StatusCode, "provider", prov.GetName(), "duration_ms", duration.Milliseconds())
 }
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"gateway/internal/logger"
+	"gateway/internal/provider"
+	"gateway/internal/retry"
+	"gateway/internal/usage"
+)
+
+// isStreamingResponse reports whether a request/response pair should be
+// relayed as a stream rather than buffered in full.
+func isStreamingResponse(requestJSON map[string]interface{}, resp *http.Response) bool {
+	if stream, _ := requestJSON["stream"].(bool); stream {
+		return true
+	}
+	return strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/event-stream")
+}
+
+// streamResponse relays an SSE response to the client frame-by-frame,
+// flushing after each one, while accumulating the reconstructed assistant
+// message and token usage for the final log entry.
+func (g *Gateway) streamResponse(w http.ResponseWriter, resp *http.Response, reservation usage.Reservation, startTime time.Time, virtualKey string, prov provider.Provider, requestJSON map[string]interface{}, attempts []retry.Attempt) {
+	// Copy response headers, excluding Content-Length which no longer applies
+	// once we relay the body as an unbounded stream.
+	for name, values := range resp.Header {
+		if strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	flusher, canFlush := w.(http.Flusher)
+
+	acc := newStreamAccumulator()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		acc.observe(line)
+
+		if _, err := w.Write([]byte(line + "\n")); err != nil {
+			slog.Error("Error writing SSE frame to client", "error", err)
+			break
+		}
+		if canFlush {
+			flusher.Flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		slog.Error("Error reading streamed response body", "error", err)
+	}
+
+	promptTokens, completionTokens := acc.tokenUsage()
+	g.tracker.Commit(reservation, promptTokens+completionTokens)
+
+	duration := time.Since(startTime)
+	g.metrics.ObserveRequest(virtualKey, prov.GetName(), resp.StatusCode, duration)
+	g.metrics.ObserveTokens(virtualKey, prov.GetName(), "prompt", promptTokens)
+	g.metrics.ObserveTokens(virtualKey, prov.GetName(), "completion", completionTokens)
+	g.metrics.SetQuotaRemaining(virtualKey, g.tracker.RemainingTokens(virtualKey, requestModel(requestJSON)))
+
+	logEntry := logger.LogEntry{
+		Timestamp:  startTime.Format(time.RFC3339),
+		VirtualKey: virtualKey,
+		Provider:   prov.GetName(),
+		Method:     http.MethodPost,
+		Status:     resp.StatusCode,
+		DurationMs: duration.Milliseconds(),
+		Request:    requestJSON,
+		Response:   acc.result(),
+		Attempts:   attempts,
+	}
+	g.logger.LogInteraction(logEntry)
+
+	slog.Info("Streamed request completed", "status", resp.StatusCode, "provider", prov.GetName(), "duration_ms", duration.Milliseconds())
+}
+
+// streamAccumulator reconstructs the assistant message and token usage from
+// a sequence of SSE "data:" frames, supporting both the OpenAI-style
+// choices[].delta.content shape and the Anthropic-style delta.text shape.
+type streamAccumulator struct {
+	content strings.Builder
+	usage   map[string]interface{}
+}
+
+func newStreamAccumulator() *streamAccumulator {
+	return &streamAccumulator{}
+}
+
+// observe parses a single SSE line and folds any delta content or usage
+// data it carries into the accumulator. Non-data lines and the terminal
+// "[DONE]" marker are ignored.
+func (a *streamAccumulator) observe(line string) {
+	data, ok := strings.CutPrefix(line, "data:")
+	if !ok {
+		return
+	}
+	data = strings.TrimSpace(data)
+	if data == "" || data == "[DONE]" {
+		return
+	}
+
+	var frame map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &frame); err != nil {
+		return
+	}
+
+	if usageObj, ok := frame["usage"].(map[string]interface{}); ok {
+		a.usage = usageObj
+	}
+
+	if choices, ok := frame["choices"].([]interface{}); ok {
+		for _, c := range choices {
+			choice, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			delta, ok := choice["delta"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if content, ok := delta["content"].(string); ok {
+				a.content.WriteString(content)
+			}
+		}
+	}
+
+	if delta, ok := frame["delta"].(map[string]interface{}); ok {
+		if text, ok := delta["text"].(string); ok {
+			a.content.WriteString(text)
+		}
+	}
+}
+
+// tokenUsage returns the prompt/input and completion/output token counts
+// observed in the terminal usage frame, if any.
+func (a *streamAccumulator) tokenUsage() (prompt, completion int) {
+	for _, field := range []string{"prompt_tokens", "input_tokens"} {
+		if n, ok := a.usage[field].(float64); ok {
+			prompt += int(n)
+		}
+	}
+	for _, field := range []string{"completion_tokens", "output_tokens"} {
+		if n, ok := a.usage[field].(float64); ok {
+			completion += int(n)
+		}
+	}
+	return prompt, completion
+}
+
+// result returns the reconstructed assistant message and usage as a map
+// suitable for the log entry's Response field.
+func (a *streamAccumulator) result() map[string]interface{} {
+	result := map[string]interface{}{"message": a.content.String()}
+	if a.usage != nil {
+		result["usage"] = a.usage
+	}
+	return result
+}
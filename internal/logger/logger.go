@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
+
+	"gateway/internal/retry"
 )
 
 const (
@@ -26,6 +28,7 @@ type LogEntry struct {
 	DurationMs int64                  `json:"duration_ms"`
 	Request    map[string]interface{} `json:"request"`
 	Response   map[string]interface{} `json:"response"`
+	Attempts   []retry.Attempt        `json:"attempts,omitempty"`
 }
 
 // Logger handles structured logging of LLM interactions
@@ -52,21 +55,19 @@ func New() (*Logger, error) {
 
 // LogInteraction logs an LLM interaction to both console and file
 func (l *Logger) LogInteraction(entry LogEntry) {
-	// Pretty-print JSON for both console and file
-	prettyJSON, err := json.MarshalIndent(entry, "", "  ")
+	// Marshal as a single line for the file
+	entryJSON, err := json.Marshal(entry)
 	if err != nil {
-		log.Printf("Error marshaling log entry: %v", err)
+		slog.Error("Error marshaling log entry", "error", err)
 		return
 	}
 
 	// Log to console
-	log.Printf("LLM Interaction Log:\n%s", string(prettyJSON))
+	slog.Info("LLM interaction", "virtual_key", entry.VirtualKey, "provider", entry.Provider, "status", entry.Status, "duration_ms", entry.DurationMs)
 
 	// Log to file
-	logOutput := string(prettyJSON) + "\n"
-
-	if _, err := l.logFile.WriteString(logOutput); err != nil {
-		log.Printf("Error writing to log file: %v", err)
+	if _, err := l.logFile.Write(append(entryJSON, '\n')); err != nil {
+		slog.Error("Error writing to log file", "error", err)
 	}
 }
 
@@ -77,13 +78,13 @@ func ParseJSONBody(body []byte, contentEncoding string) map[string]interface{} {
 	if strings.Contains(strings.ToLower(contentEncoding), "gzip") {
 		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
 		if err != nil {
-			log.Printf("Warning: Failed to create gzip reader for logging: %v", err)
+			slog.Warn("Failed to create gzip reader for logging", "error", err)
 			decompressedBody = body
 		} else {
 			decompressedBody, err = io.ReadAll(gzipReader)
 			gzipReader.Close()
 			if err != nil {
-				log.Printf("Warning: Failed to decompress body for logging: %v", err)
+				slog.Warn("Failed to decompress body for logging", "error", err)
 				decompressedBody = body
 			}
 		}
@@ -94,7 +95,7 @@ func ParseJSONBody(body []byte, contentEncoding string) map[string]interface{} {
 	// Parse JSON
 	var parsedJSON map[string]interface{}
 	if err := json.Unmarshal(decompressedBody, &parsedJSON); err != nil {
-		log.Printf("Warning: Failed to parse JSON for logging: %v", err)
+		slog.Warn("Failed to parse JSON for logging", "error", err)
 		parsedJSON = map[string]interface{}{"raw": string(decompressedBody)}
 	}
 
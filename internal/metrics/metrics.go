@@ -0,0 +1,82 @@
+// Package metrics exposes Prometheus instrumentation for the gateway,
+// served from a separate admin mux so it isn't reachable through the
+// public-facing port.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Recorder owns the gateway's Prometheus collectors.
+type Recorder struct {
+	requestsTotal   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	tokensTotal     *prometheus.CounterVec
+	upstreamErrors  *prometheus.CounterVec
+	quotaRemaining  *prometheus.GaugeVec
+}
+
+// NewRecorder creates and registers the gateway's collectors against the
+// default Prometheus registry.
+func NewRecorder() *Recorder {
+	r := &Recorder{
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_requests_total",
+			Help: "Total number of requests handled by the gateway.",
+		}, []string{"virtual_key", "provider", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "gateway_request_duration_seconds",
+			Help: "Gateway request duration in seconds.",
+		}, []string{"virtual_key", "provider"}),
+		tokensTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_tokens_total",
+			Help: "Total LLM tokens consumed, by kind.",
+		}, []string{"virtual_key", "provider", "kind"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gateway_upstream_errors_total",
+			Help: "Total upstream provider errors.",
+		}, []string{"provider", "reason"}),
+		quotaRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gateway_quota_remaining_tokens",
+			Help: "Remaining token-bucket quota per virtual key.",
+		}, []string{"virtual_key"}),
+	}
+
+	prometheus.MustRegister(r.requestsTotal, r.requestDuration, r.tokensTotal, r.upstreamErrors, r.quotaRemaining)
+	return r
+}
+
+// ObserveRequest records a completed request's status and duration.
+func (r *Recorder) ObserveRequest(virtualKey, provider string, status int, duration time.Duration) {
+	r.requestsTotal.WithLabelValues(virtualKey, provider, strconv.Itoa(status)).Inc()
+	r.requestDuration.WithLabelValues(virtualKey, provider).Observe(duration.Seconds())
+}
+
+// ObserveTokens adds count tokens of the given kind ("prompt" or
+// "completion") to the running total.
+func (r *Recorder) ObserveTokens(virtualKey, provider, kind string, count int) {
+	if count <= 0 {
+		return
+	}
+	r.tokensTotal.WithLabelValues(virtualKey, provider, kind).Add(float64(count))
+}
+
+// ObserveUpstreamError records a failed upstream provider call.
+func (r *Recorder) ObserveUpstreamError(provider, reason string) {
+	r.upstreamErrors.WithLabelValues(provider, reason).Inc()
+}
+
+// SetQuotaRemaining sets the current token-bucket level for a virtual key.
+func (r *Recorder) SetQuotaRemaining(virtualKey string, remaining float64) {
+	r.quotaRemaining.WithLabelValues(virtualKey).Set(remaining)
+}
+
+// Handler returns the Prometheus exposition handler for the admin mux.
+func (r *Recorder) Handler() http.Handler {
+	return promhttp.Handler()
+}
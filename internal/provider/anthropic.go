@@ -5,7 +5,7 @@ import "net/http"
 // Anthropic provider implementation
 type Anthropic struct{}
 
-func (a *Anthropic) GetEndpoint() string {
+func (a *Anthropic) GetEndpoint(model string) string {
 	return "https://api.anthropic.com/v1/messages"
 }
 
@@ -17,3 +17,11 @@ func (a *Anthropic) SetAuthHeaders(req *http.Request, apiKey string) {
 func (a *Anthropic) GetName() string {
 	return "anthropic"
 }
+
+func (a *Anthropic) TransformRequest(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (a *Anthropic) TransformResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
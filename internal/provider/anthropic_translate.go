@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// defaultMaxTokens is used when translating an OpenAI request that omits
+// max_tokens, since Anthropic's Messages API requires it.
+const defaultMaxTokens = 4096
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+type anthropicRequestBody struct {
+	Model       string        `json:"model"`
+	System      string        `json:"system,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature float64       `json:"temperature,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+type openAIChoice struct {
+	Index   int         `json:"index"`
+	Message chatMessage `json:"message"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type openAIResponseBody struct {
+	Choices []openAIChoice `json:"choices"`
+	Usage   openAIUsage    `json:"usage"`
+}
+
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponseBody struct {
+	Content []anthropicContentBlock `json:"content"`
+	Usage   anthropicUsage          `json:"usage"`
+}
+
+// openAIToAnthropic translates OpenAI chat completion requests into
+// Anthropic's Messages API format, and Anthropic responses back into the
+// OpenAI format.
+type openAIToAnthropic struct{}
+
+func (openAIToAnthropic) TranslateRequest(body []byte) ([]byte, error) {
+	var req openAIRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAI request: %w", err)
+	}
+
+	out := anthropicRequestBody{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		Stream:      req.Stream,
+		MaxTokens:   req.MaxTokens,
+	}
+	if out.MaxTokens == 0 {
+		out.MaxTokens = defaultMaxTokens
+	}
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			out.System = m.Content
+			continue
+		}
+		out.Messages = append(out.Messages, m)
+	}
+
+	return json.Marshal(out)
+}
+
+func (openAIToAnthropic) TranslateResponse(body []byte) ([]byte, error) {
+	var resp anthropicResponseBody
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse Anthropic response: %w", err)
+	}
+
+	var text string
+	for _, block := range resp.Content {
+		text += block.Text
+	}
+
+	out := openAIResponseBody{
+		Choices: []openAIChoice{{Message: chatMessage{Role: "assistant", Content: text}}},
+		Usage: openAIUsage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+	}
+	return json.Marshal(out)
+}
+
+// anthropicToOpenAI is the inverse of openAIToAnthropic.
+type anthropicToOpenAI struct{}
+
+func (anthropicToOpenAI) TranslateRequest(body []byte) ([]byte, error) {
+	var req anthropicRequestBody
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse Anthropic request: %w", err)
+	}
+
+	out := openAIRequest{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		Stream:      req.Stream,
+		MaxTokens:   req.MaxTokens,
+	}
+	if req.System != "" {
+		out.Messages = append(out.Messages, chatMessage{Role: "system", Content: req.System})
+	}
+	out.Messages = append(out.Messages, req.Messages...)
+
+	return json.Marshal(out)
+}
+
+func (anthropicToOpenAI) TranslateResponse(body []byte) ([]byte, error) {
+	var resp openAIResponseBody
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
+	}
+
+	var text string
+	if len(resp.Choices) > 0 {
+		text = resp.Choices[0].Message.Content
+	}
+
+	out := anthropicResponseBody{
+		Content: []anthropicContentBlock{{Type: "text", Text: text}},
+		Usage: anthropicUsage{
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+		},
+	}
+	return json.Marshal(out)
+}
@@ -5,7 +5,7 @@ import "net/http"
 // DeepSeek provider implementation
 type DeepSeek struct{}
 
-func (d *DeepSeek) GetEndpoint() string {
+func (d *DeepSeek) GetEndpoint(model string) string {
 	return "https://api.deepseek.com/v1/chat/completions"
 }
 
@@ -16,3 +16,11 @@ func (d *DeepSeek) SetAuthHeaders(req *http.Request, apiKey string) {
 func (d *DeepSeek) GetName() string {
 	return "deepseek"
 }
+
+func (d *DeepSeek) TransformRequest(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (d *DeepSeek) TransformResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const defaultGeminiModel = "gemini-pro"
+
+// Gemini implements the Provider interface for Google's Generative Language
+// API, which differs from the OpenAI-compatible providers in three ways: the
+// model is part of the endpoint path, the API key is a query parameter
+// rather than a header, and the request body uses a "contents" shape rather
+// than OpenAI's "messages".
+type Gemini struct{}
+
+func (g *Gemini) GetEndpoint(model string) string {
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	return fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent", model)
+}
+
+func (g *Gemini) SetAuthHeaders(req *http.Request, apiKey string) {
+	query := req.URL.Query()
+	query.Set("key", apiKey)
+	req.URL.RawQuery = query.Encode()
+}
+
+func (g *Gemini) GetName() string {
+	return "gemini"
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiSystemInstruction struct {
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+}
+
+type geminiRequestBody struct {
+	Contents          []geminiContent          `json:"contents"`
+	SystemInstruction *geminiSystemInstruction `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig  `json:"generationConfig,omitempty"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+}
+
+type geminiCandidate struct {
+	Content geminiContent `json:"content"`
+}
+
+type geminiResponseBody struct {
+	Candidates    []geminiCandidate    `json:"candidates"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata,omitempty"`
+}
+
+// TransformRequest converts an OpenAI-shaped chat completion body into
+// Gemini's "contents"-based generateContent request.
+func (g *Gemini) TransformRequest(body []byte) ([]byte, error) {
+	var req openAIRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse request for Gemini: %w", err)
+	}
+
+	out := geminiRequestBody{}
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			out.SystemInstruction = &geminiSystemInstruction{Parts: []geminiPart{{Text: m.Content}}}
+			continue
+		}
+		role := m.Role
+		if role == "assistant" {
+			role = "model"
+		}
+		out.Contents = append(out.Contents, geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}})
+	}
+
+	if req.Temperature != 0 || req.MaxTokens != 0 {
+		out.GenerationConfig = &geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// TransformResponse converts Gemini's "candidates"/"usageMetadata" response
+// into the OpenAI-shaped "choices"/"usage" body the rest of the gateway
+// (translation, token-bucket accounting, metrics) expects.
+func (g *Gemini) TransformResponse(body []byte) ([]byte, error) {
+	var resp geminiResponseBody
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+
+	var text string
+	if len(resp.Candidates) > 0 {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			text += part.Text
+		}
+	}
+
+	out := openAIResponseBody{
+		Choices: []openAIChoice{{Message: chatMessage{Role: "assistant", Content: text}}},
+	}
+	if resp.UsageMetadata != nil {
+		out.Usage = openAIUsage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      resp.UsageMetadata.PromptTokenCount + resp.UsageMetadata.CandidatesTokenCount,
+		}
+	}
+
+	return json.Marshal(out)
+}
@@ -0,0 +1,33 @@
+package provider
+
+import "net/http"
+
+// GenericOpenAI speaks to any OpenAI-compatible chat completions endpoint
+// configured per virtual key via KeyConfig.BaseURL, covering self-hosted or
+// third-party backends (Ollama, vLLM, LM Studio, Together, Fireworks, etc.)
+// without a dedicated provider implementation.
+type GenericOpenAI struct {
+	BaseURL string
+}
+
+func (g *GenericOpenAI) GetEndpoint(model string) string {
+	return g.BaseURL
+}
+
+func (g *GenericOpenAI) SetAuthHeaders(req *http.Request, apiKey string) {
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+func (g *GenericOpenAI) GetName() string {
+	return "generic"
+}
+
+func (g *GenericOpenAI) TransformRequest(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (g *GenericOpenAI) TransformResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
@@ -0,0 +1,26 @@
+package provider
+
+import "net/http"
+
+// Groq provider implementation, OpenAI-compatible.
+type Groq struct{}
+
+func (g *Groq) GetEndpoint(model string) string {
+	return "https://api.groq.com/openai/v1/chat/completions"
+}
+
+func (g *Groq) SetAuthHeaders(req *http.Request, apiKey string) {
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+}
+
+func (g *Groq) GetName() string {
+	return "groq"
+}
+
+func (g *Groq) TransformRequest(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (g *Groq) TransformResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
@@ -0,0 +1,26 @@
+package provider
+
+import "net/http"
+
+// Mistral provider implementation, OpenAI-compatible.
+type Mistral struct{}
+
+func (m *Mistral) GetEndpoint(model string) string {
+	return "https://api.mistral.ai/v1/chat/completions"
+}
+
+func (m *Mistral) SetAuthHeaders(req *http.Request, apiKey string) {
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+}
+
+func (m *Mistral) GetName() string {
+	return "mistral"
+}
+
+func (m *Mistral) TransformRequest(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (m *Mistral) TransformResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
@@ -5,7 +5,7 @@ import "net/http"
 // OpenAI provider implementation
 type OpenAI struct{}
 
-func (o *OpenAI) GetEndpoint() string {
+func (o *OpenAI) GetEndpoint(model string) string {
 	return "https://api.openai.com/v1/chat/completions"
 }
 
@@ -16,3 +16,11 @@ func (o *OpenAI) SetAuthHeaders(req *http.Request, apiKey string) {
 func (o *OpenAI) GetName() string {
 	return "openai"
 }
+
+func (o *OpenAI) TransformRequest(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (o *OpenAI) TransformResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
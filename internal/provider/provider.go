@@ -8,14 +8,30 @@ import (
 
 // Provider defines the interface for LLM providers
 type Provider interface {
-	// GetEndpoint returns the API endpoint URL
-	GetEndpoint() string
+	// GetEndpoint returns the API endpoint URL for the given model.
+	// Providers with a fixed endpoint ignore model.
+	GetEndpoint(model string) string
 
-	// SetAuthHeaders sets the authentication headers on the request
+	// SetAuthHeaders sets the authentication on the request, via headers or
+	// (for providers like Gemini that key off a query parameter) the URL.
 	SetAuthHeaders(req *http.Request, apiKey string)
 
 	// GetName returns the provider name
 	GetName() string
+
+	// TransformRequest converts a request body already in this provider's
+	// own wire format (see Translator for converting between providers)
+	// into whatever shape its API actually expects. Most providers speak
+	// the OpenAI chat completions shape already and return body unchanged.
+	TransformRequest(body []byte) ([]byte, error)
+
+	// TransformResponse is TransformRequest's inverse: it converts a
+	// response already received from this provider's API back into the
+	// OpenAI-compatible shape the rest of the gateway (translation, token
+	// accounting, metrics) expects. Most providers' responses are already
+	// in that shape and return body unchanged. Anthropic also returns body
+	// unchanged, so its responses stay in Anthropic's Messages shape.
+	TransformResponse(body []byte) ([]byte, error)
 }
 
 // Registry manages provider instances
@@ -30,12 +46,25 @@ func NewRegistry() *Registry {
 			"openai":    &OpenAI{},
 			"anthropic": &Anthropic{},
 			"deepseek":  &DeepSeek{},
+			"mistral":   &Mistral{},
+			"groq":      &Groq{},
+			"gemini":    &Gemini{},
 		},
 	}
 }
 
-// Get returns a provider by name
-func (r *Registry) Get(name string) (Provider, error) {
+// Get returns a provider by name. baseURL is only consulted for the
+// "generic" provider, which speaks to an OpenAI-compatible endpoint
+// configured per virtual key (Ollama, vLLM, LM Studio, Together, Fireworks,
+// etc.) rather than a hardcoded one.
+func (r *Registry) Get(name, baseURL string) (Provider, error) {
+	if strings.ToLower(name) == "generic" {
+		if baseURL == "" {
+			return nil, fmt.Errorf("provider %q requires a base_url", name)
+		}
+		return &GenericOpenAI{BaseURL: baseURL}, nil
+	}
+
 	provider, exists := r.providers[strings.ToLower(name)]
 	if !exists {
 		return nil, fmt.Errorf("unsupported provider: %s", name)
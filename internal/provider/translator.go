@@ -0,0 +1,52 @@
+package provider
+
+import "fmt"
+
+// Translator converts a request/response body between two providers' wire
+// formats, so a request built for one API can be replayed against another
+// during failover.
+type Translator interface {
+	TranslateRequest(body []byte) ([]byte, error)
+	TranslateResponse(body []byte) ([]byte, error)
+}
+
+// identityTranslator passes bodies through unchanged, for providers that
+// already share a wire format (e.g. OpenAI and DeepSeek).
+type identityTranslator struct{}
+
+func (identityTranslator) TranslateRequest(body []byte) ([]byte, error)  { return body, nil }
+func (identityTranslator) TranslateResponse(body []byte) ([]byte, error) { return body, nil }
+
+// TranslatorFor returns the Translator that converts requests from one
+// provider's wire format (from) to another's (to), and responses back from
+// to's format into from's.
+func TranslatorFor(from, to string) (Translator, error) {
+	if from == to {
+		return identityTranslator{}, nil
+	}
+
+	switch {
+	case isOpenAICompatible(from) && isOpenAICompatible(to):
+		return identityTranslator{}, nil
+	case from == "openai" && to == "anthropic":
+		return openAIToAnthropic{}, nil
+	case from == "anthropic" && to == "openai":
+		return anthropicToOpenAI{}, nil
+	default:
+		return nil, fmt.Errorf("no translator registered for %s -> %s", from, to)
+	}
+}
+
+// isOpenAICompatible reports whether a provider name speaks the OpenAI chat
+// completions wire format at the Translator layer. Gemini's bespoke
+// "contents"/"candidates" shape is confined to its own TransformRequest/
+// TransformResponse, which converts to and from this same OpenAI shape, so
+// it belongs here too.
+func isOpenAICompatible(name string) bool {
+	switch name {
+	case "openai", "deepseek", "mistral", "groq", "generic", "gemini":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,155 @@
+// Package retry implements exponential backoff with jitter for retrying
+// proxied provider requests, and records the outcome of every attempt.
+package retry
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Config controls retry/backoff behavior for a single proxied request.
+type Config struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultConfig is the retry configuration used for every proxied request;
+// virtual keys have no retry settings of their own.
+var DefaultConfig = Config{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// Attempt records the outcome of a single try at forwarding a request to a
+// provider, for inclusion in the interaction log.
+type Attempt struct {
+	Provider   string `json:"provider"`
+	Status     int    `json:"status,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Backoff returns the delay before the given (zero-based) retry attempt:
+// base * 2^attempt, capped at MaxDelay, scaled by a jitter factor in
+// [0.5, 1.0).
+func Backoff(cfg Config, attempt int) time.Duration {
+	delay := float64(cfg.BaseDelay) * math.Pow(2, float64(attempt))
+	if capped := float64(cfg.MaxDelay); delay > capped {
+		delay = capped
+	}
+	return time.Duration(delay * (0.5 + rand.Float64()*0.5))
+}
+
+// Retryable reports whether an upstream status code warrants a retry:
+// connection-level failures, 429, and 5xx other than 501 Not Implemented.
+func Retryable(status int) bool {
+	if status == http.StatusNotImplemented {
+		return false
+	}
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// RetryAfter parses a Retry-After header (either delay-seconds or an
+// HTTP-date) into a duration.
+func RetryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// Do calls attempt up to cfg.MaxAttempts times, retrying on connection
+// errors or a Retryable status with exponential backoff (honoring any
+// Retry-After header on 429s, capped at cfg.MaxDelay so a provider can't
+// park the request indefinitely). It stops early if ctx is cancelled while
+// waiting between attempts.
+//
+// The returned error is non-nil whenever the chain didn't end in a
+// non-retryable response: either every attempt failed outright,
+// or the final attempt still came back with a Retryable status. In the
+// latter case the final response is returned alongside the error (rather
+// than nil) so callers can fall back to another provider while still having
+// the last upstream response available if the whole chain is exhausted.
+func Do(ctx context.Context, cfg Config, providerName string, attempt func() (*http.Response, error)) (*http.Response, []Attempt, error) {
+	var attempts []Attempt
+	var lastErr error
+
+	for i := 0; i < cfg.MaxAttempts; i++ {
+		start := time.Now()
+		resp, err := attempt()
+		duration := time.Since(start).Milliseconds()
+
+		if err != nil {
+			attempts = append(attempts, Attempt{Provider: providerName, DurationMs: duration, Error: err.Error()})
+			lastErr = err
+			if i == cfg.MaxAttempts-1 {
+				break
+			}
+			if sleepErr := sleepCtx(ctx, Backoff(cfg, i)); sleepErr != nil {
+				lastErr = sleepErr
+				break
+			}
+			continue
+		}
+
+		attempts = append(attempts, Attempt{Provider: providerName, Status: resp.StatusCode, DurationMs: duration})
+
+		if !Retryable(resp.StatusCode) {
+			return resp, attempts, nil
+		}
+
+		lastErr = &StatusError{Status: resp.StatusCode}
+		if i == cfg.MaxAttempts-1 {
+			return resp, attempts, lastErr
+		}
+
+		wait := Backoff(cfg, i)
+		if retryAfter, ok := RetryAfter(resp.Header); ok {
+			wait = retryAfter
+			if wait > cfg.MaxDelay {
+				wait = cfg.MaxDelay
+			}
+		}
+		if sleepErr := sleepCtx(ctx, wait); sleepErr != nil {
+			resp.Body.Close()
+			lastErr = sleepErr
+			break
+		}
+		resp.Body.Close()
+	}
+
+	return nil, attempts, lastErr
+}
+
+// sleepCtx waits for d, returning early with ctx.Err() if ctx is done first
+// (e.g. the client disconnected), so a slow Retry-After doesn't tie up the
+// handler for a client that's no longer listening.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// StatusError reports that every retry was exhausted against a retryable
+// upstream status code.
+type StatusError struct {
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return "exhausted retries against upstream status " + strconv.Itoa(e.Status)
+}
@@ -2,93 +2,251 @@ package usage
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 )
 
-// VirtualKeyUsage tracks usage for a single virtual key
-type VirtualKeyUsage struct {
-	requestCount int
-	windowStart  time.Time
+// ModelPolicy overrides the default rate limits for a specific model.
+type ModelPolicy struct {
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+	TokensPerMinute   float64 `json:"tokens_per_minute"`
+	Burst             float64 `json:"burst"`
 }
 
-// Tracker manages per-virtual-key usage quotas
-type Tracker struct {
-	mu                 sync.RWMutex
-	usage              map[string]*VirtualKeyUsage
-	maxRequestsPerHour int
+// Policy describes the token-bucket limits applied to a virtual key, with
+// optional per-model overrides layered on top of the defaults.
+type Policy struct {
+	RequestsPerMinute float64                `json:"requests_per_minute"`
+	TokensPerMinute   float64                `json:"tokens_per_minute"`
+	Burst             float64                `json:"burst"`
+	Models            map[string]ModelPolicy `json:"models,omitempty"`
 }
 
-// New creates a new usage tracker with per-key hourly quotas
-func New(maxRequestsPerHour int) *Tracker {
-	return &Tracker{
-		usage:              make(map[string]*VirtualKeyUsage),
-		maxRequestsPerHour: maxRequestsPerHour,
+// forModel resolves the effective limits for a given model, applying any
+// per-model override on top of the key's defaults.
+func (p Policy) forModel(model string) ModelPolicy {
+	effective := ModelPolicy{
+		RequestsPerMinute: p.RequestsPerMinute,
+		TokensPerMinute:   p.TokensPerMinute,
+		Burst:             p.Burst,
+	}
+	override, ok := p.Models[model]
+	if !ok {
+		return effective
+	}
+	if override.RequestsPerMinute > 0 {
+		effective.RequestsPerMinute = override.RequestsPerMinute
 	}
+	if override.TokensPerMinute > 0 {
+		effective.TokensPerMinute = override.TokensPerMinute
+	}
+	if override.Burst > 0 {
+		effective.Burst = override.Burst
+	}
+	return effective
+}
+
+// bucket is a token bucket refilled at a fixed rate up to a capacity. A
+// bucket with capacity <= 0 is unlimited: a zero or unset limit means "no
+// limit configured" rather than "no quota", so unconfigured and
+// partially-configured policies don't reject every request.
+type bucket struct {
+	tokens     float64
+	capacity   float64
+	refillRate float64 // tokens per second
+	updatedAt  time.Time
+	unlimited  bool
 }
 
-// getOrCreateUsage gets or initializes usage data for a virtual key
-// Must be called with lock held
-func (t *Tracker) getOrCreateUsage(virtualKey string) *VirtualKeyUsage {
-	if usage, exists := t.usage[virtualKey]; exists {
-		return usage
+func newBucket(capacity, refillRate float64) *bucket {
+	if capacity <= 0 {
+		return &bucket{unlimited: true}
 	}
+	return &bucket{tokens: capacity, capacity: capacity, refillRate: refillRate, updatedAt: time.Now()}
+}
 
-	// Initialize new virtual key usage
-	t.usage[virtualKey] = &VirtualKeyUsage{
-		requestCount: 0,
-		windowStart:  time.Now(),
+// reconfigure updates the bucket's capacity and refill rate in place, for a
+// policy change (e.g. a config hot-reload) on a key/model already being
+// tracked. The current token level is preserved rather than reset, clamped
+// to the new capacity, so an in-flight burst isn't arbitrarily replenished
+// or truncated beyond what the new limit allows.
+func (b *bucket) reconfigure(capacity, refillRate float64) {
+	if capacity <= 0 {
+		b.unlimited = true
+		return
+	}
+	if b.unlimited {
+		b.tokens = capacity
+		b.updatedAt = time.Now()
+	} else if b.tokens > capacity {
+		b.tokens = capacity
 	}
-	return t.usage[virtualKey]
+	b.unlimited = false
+	b.capacity = capacity
+	b.refillRate = refillRate
 }
 
-// CheckQuota checks if the request is within quota for the given virtual key
-// Returns an error if quota is exceeded
-func (t *Tracker) CheckQuota(virtualKey string) error {
+// refill tops up the bucket based on elapsed time since the last refill.
+func (b *bucket) refill(now time.Time) {
+	if b.unlimited {
+		return
+	}
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.refillRate)
+	b.updatedAt = now
+}
+
+// take debits cost tokens if available. On failure it returns the wait
+// until cost tokens would be available at the current refill rate.
+func (b *bucket) take(now time.Time, cost float64) (bool, time.Duration) {
+	if b.unlimited {
+		return true, 0
+	}
+	b.refill(now)
+	if b.tokens >= cost {
+		b.tokens -= cost
+		return true, 0
+	}
+	if b.refillRate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	deficit := cost - b.tokens
+	return false, time.Duration(deficit / b.refillRate * float64(time.Second))
+}
+
+// keyModelBuckets holds the request-rate and token-rate buckets for a single
+// (virtual key, model) pair, along with the ModelPolicy they were last
+// configured from, so a changed policy can be detected and applied on the
+// next Reserve rather than frozen at first use.
+type keyModelBuckets struct {
+	requests *bucket
+	tokens   *bucket
+	policy   ModelPolicy
+}
+
+// Reservation represents a provisional debit against a bucket, made before
+// the provider's actual token usage is known. Commit trues it up.
+type Reservation struct {
+	virtualKey     string
+	model          string
+	reservedTokens float64
+}
+
+// LimitError indicates a Reserve call was rejected because a bucket is
+// exhausted. RetryAfter is the minimum wait before the request would succeed.
+type LimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// Tracker manages per-(virtual key, model) token-bucket quotas, covering both
+// request rate and LLM token throughput.
+type Tracker struct {
+	mu      sync.Mutex
+	buckets map[string]*keyModelBuckets
+}
+
+// New creates a new usage tracker.
+func New() *Tracker {
+	return &Tracker{buckets: make(map[string]*keyModelBuckets)}
+}
+
+func bucketKey(virtualKey, model string) string {
+	return virtualKey + "|" + model
+}
+
+// getOrCreateBuckets returns the buckets for (virtualKey, model), creating
+// them against mp if this is the first time they're seen, or reconfiguring
+// them in place if mp has changed since (e.g. a config hot-reload).
+func (t *Tracker) getOrCreateBuckets(virtualKey, model string, mp ModelPolicy) *keyModelBuckets {
+	key := bucketKey(virtualKey, model)
+	requestCapacity := mp.Burst
+	if requestCapacity <= 0 {
+		requestCapacity = mp.RequestsPerMinute
+	}
+
+	if b, exists := t.buckets[key]; exists {
+		if b.policy != mp {
+			b.requests.reconfigure(requestCapacity, mp.RequestsPerMinute/60)
+			b.tokens.reconfigure(mp.TokensPerMinute, mp.TokensPerMinute/60)
+			b.policy = mp
+		}
+		return b
+	}
+
+	b := &keyModelBuckets{
+		requests: newBucket(requestCapacity, mp.RequestsPerMinute/60),
+		tokens:   newBucket(mp.TokensPerMinute, mp.TokensPerMinute/60),
+		policy:   mp,
+	}
+	t.buckets[key] = b
+	return b
+}
+
+// estimatedRequestTokens is the conservative token cost debited at Reserve
+// time, before the provider has reported actual usage. Commit trues this up.
+const estimatedRequestTokens = 1
+
+// Reserve checks the request-rate and token-rate buckets for (virtualKey,
+// model) against policy, debiting both on success. Callers must call Commit
+// once the real token usage is known, even on provider error.
+func (t *Tracker) Reserve(virtualKey, model string, policy Policy) (Reservation, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	usage := t.getOrCreateUsage(virtualKey)
+	mp := policy.forModel(model)
+	buckets := t.getOrCreateBuckets(virtualKey, model, mp)
+	now := time.Now()
 
-	// Reset counter if we've moved to a new hour window
-	if time.Since(usage.windowStart) >= time.Hour {
-		usage.requestCount = 0
-		usage.windowStart = time.Now()
+	if ok, wait := buckets.requests.take(now, 1); !ok {
+		return Reservation{}, &LimitError{RetryAfter: wait}
 	}
 
-	// Check if quota exceeded
-	if usage.requestCount >= t.maxRequestsPerHour {
-		return fmt.Errorf("quota exceeded: %d requests per hour limit reached", t.maxRequestsPerHour)
+	if ok, wait := buckets.tokens.take(now, estimatedRequestTokens); !ok {
+		buckets.requests.tokens++ // refund the request-rate debit above
+		return Reservation{}, &LimitError{RetryAfter: wait}
 	}
 
-	return nil
+	return Reservation{virtualKey: virtualKey, model: model, reservedTokens: estimatedRequestTokens}, nil
 }
 
-// RecordRequest increments the request counter for the given virtual key
-func (t *Tracker) RecordRequest(virtualKey string) {
+// Commit reconciles a Reservation against the actual number of tokens the
+// provider reported consuming, debiting (or refunding) the difference.
+func (t *Tracker) Commit(res Reservation, tokensUsed int) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	usage := t.getOrCreateUsage(virtualKey)
-
-	// Reset counter if we've moved to a new hour window
-	if time.Since(usage.windowStart) >= time.Hour {
-		usage.requestCount = 0
-		usage.windowStart = time.Now()
+	buckets, exists := t.buckets[bucketKey(res.virtualKey, res.model)]
+	if !exists {
+		return
 	}
 
-	usage.requestCount++
+	buckets.tokens.refill(time.Now())
+	delta := float64(tokensUsed) - res.reservedTokens
+	buckets.tokens.tokens = math.Max(0, buckets.tokens.tokens-delta)
 }
 
-// GetStats returns current usage statistics for a specific virtual key
-func (t *Tracker) GetStats(virtualKey string) (requestCount int, maxRequests int, windowStart time.Time) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
+// RemainingTokens returns the current token-bucket level for (virtualKey,
+// model), for exposing as a quota-remaining gauge.
+func (t *Tracker) RemainingTokens(virtualKey, model string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	if usage, exists := t.usage[virtualKey]; exists {
-		return usage.requestCount, t.maxRequestsPerHour, usage.windowStart
+	buckets, exists := t.buckets[bucketKey(virtualKey, model)]
+	if !exists {
+		return 0
+	}
+	if buckets.tokens.unlimited {
+		return math.Inf(1)
 	}
 
-	// Return zero values for unknown key
-	return 0, t.maxRequestsPerHour, time.Time{}
+	buckets.tokens.refill(time.Now())
+	return buckets.tokens.tokens
 }
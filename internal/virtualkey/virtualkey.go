@@ -1,44 +1,26 @@
 package virtualkey
 
 import (
-	"fmt"
-	"strings"
-
 	"gateway/internal/config"
 )
 
-// Service handles virtual key validation and lookup
+// Service looks up the per-key configuration (provider, API key, policy) for
+// an already-authenticated virtual key. Authentication itself lives in the
+// auth package, which is pluggable across schemes.
+//
+// Service reads from the store on every call rather than caching a
+// snapshot, so a reloaded keys file takes effect immediately.
 type Service struct {
-	config *config.Config
-}
-
-// New creates a new virtual key service
-func New(cfg *config.Config) *Service {
-	return &Service{config: cfg}
+	store *config.Store
 }
 
-// ValidateRequest validates the Authorization header and returns the virtual key
-func (s *Service) ValidateRequest(authHeader string) (string, error) {
-	if authHeader == "" {
-		return "", fmt.Errorf("missing Authorization header")
-	}
-
-	// Extract bearer token
-	virtualKey := strings.TrimPrefix(authHeader, "Bearer ")
-	if virtualKey == authHeader {
-		return "", fmt.Errorf("invalid Authorization header format, expected 'Bearer <virtual-key>'")
-	}
-
-	// Look up virtual key in config
-	if _, exists := s.config.VirtualKeys[virtualKey]; !exists {
-		return "", fmt.Errorf("invalid virtual key")
-	}
-
-	return virtualKey, nil
+// New creates a new virtual key service backed by store.
+func New(store *config.Store) *Service {
+	return &Service{store: store}
 }
 
 // GetKeyConfig returns the configuration for a virtual key
 func (s *Service) GetKeyConfig(virtualKey string) (config.KeyConfig, bool) {
-	keyConfig, exists := s.config.VirtualKeys[virtualKey]
+	keyConfig, exists := s.store.Get().VirtualKeys[virtualKey]
 	return keyConfig, exists
 }
@@ -1,324 +1,242 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gateway/internal/auth"
+	"gateway/internal/cache"
+	"gateway/internal/config"
+	"gateway/internal/gateway"
+	"gateway/internal/logger"
+	"gateway/internal/metrics"
+	"gateway/internal/provider"
+	"gateway/internal/usage"
+	"gateway/internal/virtualkey"
 )
 
 const (
-	// Gateway endpoint
-	chatCompletionsPath = "/chat/completions"
-
 	// Default port
 	defaultPort = "8080"
 
 	// Default keys file
 	defaultKeysFile = "keys.json"
 
-	// Logging
-	logsDir     = "logs"
-	logFilename = "llm_interactions.jsonl"
-
-	// Provider URLs
-	openAIURL    = "https://api.openai.com/v1/chat/completions"
-	anthropicURL = "https://api.anthropic.com/v1/messages"
-	deepseekURL  = "https://api.deepseek.com/v1/chat/completions"
-)
+	// Default auth scheme
+	defaultAuthScheme = "static://"
 
-// KeyConfig represents a single virtual key configuration
-type KeyConfig struct {
-	Provider string `json:"provider"`
-	APIKey   string `json:"api_key"`
-}
-
-// Config represents the keys.json structure
-type Config struct {
-	VirtualKeys map[string]KeyConfig `json:"virtual_keys"`
-}
+	// Default admin port, serving /metrics and /admin/reload
+	defaultAdminPort = "9090"
 
-// LogEntry represents a logged LLM interaction
-type LogEntry struct {
-	Timestamp  string                 `json:"timestamp"`
-	VirtualKey string                 `json:"virtual_key"`
-	Provider   string                 `json:"provider"`
-	Method     string                 `json:"method"`
-	Status     int                    `json:"status"`
-	DurationMs int64                  `json:"duration_ms"`
-	Request    map[string]interface{} `json:"request"`
-	Response   map[string]interface{} `json:"response"`
-}
+	// Default response cache settings
+	defaultCacheCapacity = 1000
+	defaultCacheTTL      = 5 * time.Minute
+)
 
-type Gateway struct {
-	config  *Config
-	logFile *os.File
-}
+func main() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
 
-func LoadConfig(filepath string) (*Config, error) {
-	file, err := os.ReadFile(filepath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	// Get configuration from environment variables or use defaults
+	keysFile := os.Getenv("KEYS_FILE")
+	if keysFile == "" {
+		keysFile = defaultKeysFile
 	}
 
-	var config Config
-	if err := json.Unmarshal(file, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config JSON: %w", err)
+	port := os.Getenv("GATEWAY_PORT")
+	if port == "" {
+		port = defaultPort
 	}
 
-	return &config, nil
-}
-
-func InitLogging() (*os.File, error) {
-	// Create logs directory if it doesn't exist
-	if err := os.MkdirAll(logsDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create logs directory: %w", err)
+	adminPort := os.Getenv("ADMIN_PORT")
+	if adminPort == "" {
+		adminPort = defaultAdminPort
 	}
 
-	// Open log file in append mode
-	logPath := fmt.Sprintf("%s/%s", logsDir, logFilename)
-	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// Load configuration into a hot-reloadable store, watching the keys
+	// file for changes and SIGHUP
+	store, err := config.NewStore(keysFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
-	}
-
-	return logFile, nil
-}
-
-func NewGateway(config *Config, logFile *os.File) *Gateway {
-	return &Gateway{
-		config:  config,
-		logFile: logFile,
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
 	}
-}
-
-func (g *Gateway) logInteraction(entry LogEntry) {
-	// Log to console (pretty-printed)
-	consoleJSON, err := json.MarshalIndent(entry, "", "  ")
-	if err != nil {
-		log.Printf("Error marshaling log entry for console: %v", err)
-	} else {
-		log.Printf("LLM Interaction Log:\n%s", string(consoleJSON))
+	if err := store.Watch(); err != nil {
+		slog.Error("Failed to watch configuration file", "error", err)
+		os.Exit(1)
 	}
 
-	// Log to file (single line JSON)
-	fileJSON, err := json.Marshal(entry)
+	// Initialize logging
+	logInstance, err := logger.New()
 	if err != nil {
-		log.Printf("Error marshaling log entry for file: %v", err)
-		return
+		slog.Error("Failed to initialize logging", "error", err)
+		os.Exit(1)
 	}
+	defer logInstance.Close()
 
-	if _, err := g.logFile.Write(append(fileJSON, '\n')); err != nil {
-		log.Printf("Error writing to log file: %v", err)
-	}
-}
-
-func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Start timing
-	startTime := time.Now()
-
-	// Only handle the chat completions endpoint
-	if r.URL.Path != chatCompletionsPath {
-		http.NotFound(w, r)
-		return
-	}
-
-	// Only handle POST requests
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+	// Build the configured auth scheme
+	authScheme := os.Getenv("AUTH_SCHEME")
+	if authScheme == "" {
+		authScheme = defaultAuthScheme
 	}
-
-	// Extract virtual key from Authorization header
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-		log.Println("Request rejected: missing Authorization header")
-		return
-	}
-
-	// Extract bearer token
-	virtualKey := strings.TrimPrefix(authHeader, "Bearer ")
-	if virtualKey == authHeader {
-		http.Error(w, "Invalid Authorization header format. Expected 'Bearer <virtual-key>'", http.StatusUnauthorized)
-		log.Println("Request rejected: invalid Authorization header format")
-		return
-	}
-
-	// Look up virtual key in config
-	keyConfig, exists := g.config.VirtualKeys[virtualKey]
-	if !exists {
-		http.Error(w, "Invalid virtual key", http.StatusUnauthorized)
-		log.Printf("Request rejected: invalid virtual key: %s", virtualKey)
-		return
-	}
-
-	// Read and buffer the request body
-	requestBody, err := io.ReadAll(r.Body)
+	authenticator, err := auth.NewAuth(authScheme, store)
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
-		log.Printf("Error reading request body: %v", err)
-		return
+		slog.Error("Failed to initialize auth scheme", "scheme", authScheme, "error", err)
+		os.Exit(1)
 	}
-	defer r.Body.Close()
 
-	// Parse request JSON for logging
-	var requestJSON map[string]interface{}
-	if err := json.Unmarshal(requestBody, &requestJSON); err != nil {
-		log.Printf("Warning: Failed to parse request JSON for logging: %v", err)
-		requestJSON = map[string]interface{}{"raw": string(requestBody)}
-	}
-
-	// Determine target URL based on provider
-	var targetURL string
-	switch strings.ToLower(keyConfig.Provider) {
-	case "openai":
-		targetURL = openAIURL
-	case "anthropic":
-		targetURL = anthropicURL
-	case "deepseek":
-		targetURL = deepseekURL
-	default:
-		http.Error(w, "Unsupported provider", http.StatusInternalServerError)
-		log.Printf("Unsupported provider: %s", keyConfig.Provider)
-		return
-	}
-
-	log.Printf("Routing request to %s provider (virtual key: %s)", keyConfig.Provider, virtualKey)
-
-	// Create a new request with buffered body
-	proxyReq, err := http.NewRequest(r.Method, targetURL, bytes.NewReader(requestBody))
+	// Build the response cache
+	respCache, err := buildCache()
 	if err != nil {
-		http.Error(w, "Failed to create proxy request", http.StatusInternalServerError)
-		log.Printf("Error creating proxy request: %v", err)
+		slog.Error("Failed to initialize response cache", "error", err)
+		os.Exit(1)
+	}
+
+	// Wire up the gateway
+	vkService := virtualkey.New(store)
+	providerRegistry := provider.NewRegistry()
+	tracker := usage.New()
+	recorder := metrics.NewRecorder()
+	gw := gateway.New(authenticator, vkService, providerRegistry, logInstance, tracker, recorder, respCache)
+
+	// Serve Prometheus metrics and the reload endpoint on a separate admin
+	// mux so they aren't reachable through the public gateway port
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", recorder.Handler())
+	adminMux.Handle("/admin/reload", reloadHandler(store, os.Getenv("ADMIN_RELOAD_TOKEN")))
+	go func() {
+		slog.Info("Starting admin server", "port", adminPort)
+		if err := http.ListenAndServe(":"+adminPort, adminMux); err != nil {
+			slog.Error("Admin server failed", "error", err)
+		}
+	}()
+
+	cfg := store.Get()
+	slog.Info("Starting LLM Gateway Router", "port", port)
+	slog.Info("Loaded configuration", "keys_file", keysFile)
+	slog.Info("Configured virtual keys", "count", len(cfg.VirtualKeys))
+	for vk, kc := range cfg.VirtualKeys {
+		slog.Info("Virtual key mapping", "virtual_key", vk, "provider", kc.Provider)
+	}
+
+	// The cert:// auth scheme authenticates requests off the client's TLS
+	// certificate, which only exists once the listener itself requires and
+	// verifies one.
+	if strings.HasPrefix(authScheme, "cert://") {
+		if err := listenAndServeClientCertTLS(port, gw); err != nil {
+			slog.Error("Server failed to start", "error", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	// Copy headers from original request (excluding Authorization)
-	for name, values := range r.Header {
-		if name != "Authorization" {
-			for _, value := range values {
-				proxyReq.Header.Add(name, value)
-			}
-		}
+	if err := http.ListenAndServe(":"+port, gw); err != nil {
+		slog.Error("Server failed to start", "error", err)
+		os.Exit(1)
 	}
+}
 
-	// Set the real API key based on provider
-	switch strings.ToLower(keyConfig.Provider) {
-	case "openai", "deepseek":
-		// OpenAI and DeepSeek use Bearer token authentication
-		proxyReq.Header.Set("Authorization", "Bearer "+keyConfig.APIKey)
-	case "anthropic":
-		// Anthropic uses x-api-key header
-		proxyReq.Header.Set("x-api-key", keyConfig.APIKey)
-		proxyReq.Header.Set("anthropic-version", "2023-06-01")
+// listenAndServeClientCertTLS serves gw over TLS, requiring and verifying a
+// client certificate against TLS_CLIENT_CA_FILE, using the server's own
+// certificate/key from TLS_CERT_FILE/TLS_KEY_FILE.
+func listenAndServeClientCertTLS(port string, gw http.Handler) error {
+	certFile := os.Getenv("TLS_CERT_FILE")
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	clientCAFile := os.Getenv("TLS_CLIENT_CA_FILE")
+	if certFile == "" || keyFile == "" || clientCAFile == "" {
+		return fmt.Errorf("cert:// auth scheme requires TLS_CERT_FILE, TLS_KEY_FILE, and TLS_CLIENT_CA_FILE")
 	}
 
-	// Forward the request
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
+	caCert, err := os.ReadFile(clientCAFile)
 	if err != nil {
-		http.Error(w, "Failed to forward request", http.StatusBadGateway)
-		log.Printf("Error forwarding request: %v", err)
-		return
+		return fmt.Errorf("failed to read TLS_CLIENT_CA_FILE: %w", err)
 	}
-	defer resp.Body.Close()
-
-	// Read and buffer the response body
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response body", http.StatusBadGateway)
-		log.Printf("Error reading response body: %v", err)
-		return
-	}
-
-	// Parse response JSON for logging
-	var responseJSON map[string]interface{}
-	if err := json.Unmarshal(responseBody, &responseJSON); err != nil {
-		log.Printf("Warning: Failed to parse response JSON for logging: %v", err)
-		responseJSON = map[string]interface{}{"raw": string(responseBody)}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caCert) {
+		return fmt.Errorf("failed to parse TLS_CLIENT_CA_FILE %q: no certificates found", clientCAFile)
 	}
 
-	// Calculate duration
-	duration := time.Since(startTime)
-
-	// Create log entry
-	logEntry := LogEntry{
-		Timestamp:  startTime.Format(time.RFC3339),
-		VirtualKey: virtualKey,
-		Provider:   keyConfig.Provider,
-		Method:     r.Method,
-		Status:     resp.StatusCode,
-		DurationMs: duration.Milliseconds(),
-		Request:    requestJSON,
-		Response:   responseJSON,
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: gw,
+		TLSConfig: &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		},
 	}
 
-	// Log the interaction
-	g.logInteraction(logEntry)
+	slog.Info("Starting LLM Gateway Router with client-certificate TLS", "port", port)
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
 
-	// Copy response headers to client
-	for name, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(name, value)
+// buildCache constructs the response cache backend named by CACHE_BACKEND
+// ("memory", the default, or "redis"), sized and timed out per the
+// CACHE_CAPACITY and CACHE_TTL environment variables.
+func buildCache() (cache.Cache, error) {
+	ttl := defaultCacheTTL
+	if raw := os.Getenv("CACHE_TTL"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CACHE_TTL: %w", err)
 		}
+		ttl = parsed
 	}
 
-	// Set status code
-	w.WriteHeader(resp.StatusCode)
+	switch backend := os.Getenv("CACHE_BACKEND"); backend {
+	case "redis":
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("REDIS_ADDR must be set when CACHE_BACKEND=redis")
+		}
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		return cache.NewRedis(client, ttl), nil
+
+	case "", "memory":
+		capacity := defaultCacheCapacity
+		if raw := os.Getenv("CACHE_CAPACITY"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CACHE_CAPACITY: %w", err)
+			}
+			capacity = parsed
+		}
+		return cache.NewMemory(capacity, ttl), nil
 
-	// Write buffered response body to client
-	if _, err := w.Write(responseBody); err != nil {
-		log.Printf("Error writing response body to client: %v", err)
+	default:
+		return nil, fmt.Errorf("unsupported CACHE_BACKEND: %s", backend)
 	}
-
-	log.Printf("Request completed with status: %d (provider: %s, duration: %dms)", resp.StatusCode, keyConfig.Provider, duration.Milliseconds())
 }
 
-func main() {
-	// Get configuration from environment variables or use defaults
-	keysFile := os.Getenv("KEYS_FILE")
-	if keysFile == "" {
-		keysFile = defaultKeysFile
-	}
-
-	port := os.Getenv("GATEWAY_PORT")
-	if port == "" {
-		port = defaultPort
-	}
-
-	// Load configuration
-	config, err := LoadConfig(keysFile)
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
-	}
-
-	// Initialize logging
-	logFile, err := InitLogging()
-	if err != nil {
-		log.Fatalf("Failed to initialize logging: %v", err)
-	}
-	defer logFile.Close()
-
-	// Create gateway
-	gateway := NewGateway(config, logFile)
-
-	log.Printf("Starting LLM Gateway Router on port %s", port)
-	log.Printf("Loaded configuration from: %s", keysFile)
-	log.Printf("Logging to: %s/%s", logsDir, logFilename)
-	log.Printf("Endpoint: POST %s", chatCompletionsPath)
-	log.Printf("Configured virtual keys: %d", len(config.VirtualKeys))
-	log.Println("Virtual key mappings:")
-	for vk, kc := range config.VirtualKeys {
-		log.Printf("  %s -> %s provider", vk, kc.Provider)
-	}
+// reloadHandler returns an HTTP handler that re-reads and validates the
+// config store's keys file on demand, guarded by a separately-configured
+// admin token distinct from any virtual key.
+func reloadHandler(store *config.Store, adminToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			http.Error(w, "reload endpoint is disabled: ADMIN_RELOAD_TOKEN is not set", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+adminToken {
+			http.Error(w, "invalid or missing admin token", http.StatusUnauthorized)
+			return
+		}
 
-	if err := http.ListenAndServe(":"+port, gateway); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+		if err := store.Reload(); err != nil {
+			http.Error(w, "reload failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("configuration reloaded\n"))
 	}
 }